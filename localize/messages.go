@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 
 	loc "github.com/ArdanStudios/go-common/i18n"
 	"github.com/goinggo/tracelog"
@@ -16,11 +17,13 @@ import (
 	"github.com/nicksnyder/go-i18n/i18n/translation"
 )
 
-var (
-	// T is the translate function for the specified user
-	// locale and default locale specified during the load
-	T i18n.TranslateFunc
-)
+// T is the translate function for the specified user locale and default
+// locale specified during the load. It always delegates to the translator
+// most recently installed by LoadJSON, LoadFiles or a Watch-triggered
+// reload, so it is safe to call concurrently with those: see CurrentT
+var T i18n.TranslateFunc = func(translationID string, args ...interface{}) string {
+	return CurrentT()(translationID, args...)
+}
 
 // Init initializes the local environment
 func Init(userLocale string) {
@@ -51,10 +54,14 @@ func LoadJSON(userLocale string, translationDocument string) error {
 	}
 
 	// Create a translation function for use
-	T, err = i18n.Tfunc(userLocale, userLocale)
+	t, err := i18n.Tfunc(userLocale, userLocale)
 	if err != nil {
 		return err
 	}
+	setT(t)
+
+	// Remember the locales used so Watch can recompute T after a reload
+	rememberLocales(userLocale, userLocale)
 
 	return nil
 }
@@ -78,10 +85,14 @@ func LoadFiles(userLocale string, defaultLocal string) error {
 	}
 
 	// Create a translation function for use
-	T, err = i18n.Tfunc(userLocale, defaultLocal)
+	t, err := i18n.Tfunc(userLocale, defaultLocal)
 	if err != nil {
 		return err
 	}
+	setT(t)
+
+	// Remember the locales used so Watch can recompute T after a reload
+	rememberLocales(userLocale, defaultLocal)
 
 	return nil
 }
@@ -89,6 +100,31 @@ func LoadFiles(userLocale string, defaultLocal string) error {
 // searchDirectory recurses through the specified directory looking
 // for i18n folders. If found it will load the translations files
 func searchDirectory(directory string, pwd string) {
+	searchDirectoryVisited(directory, pwd, map[string]bool{})
+}
+
+// searchDirectoryVisited is the recursive worker behind searchDirectory. It
+// tracks the real (symlink-resolved) path of every directory it has already
+// walked so that a GOPATH containing pwd, or a symlink cycle, can't send it
+// into an infinite loop
+func searchDirectoryVisited(directory string, pwd string, visited map[string]bool) {
+	realPath, err := filepath.EvalSymlinks(directory)
+	if err != nil {
+		realPath = directory
+	}
+
+	if visited[realPath] == true {
+		return
+	}
+	visited[realPath] = true
+
+	// Remember this directory too, not just i18n leaves below: fsnotify only
+	// delivers Create events for children of directories that are
+	// themselves watched, so a brand new i18n folder appearing anywhere
+	// under pwd/GOPATH needs its parent watched, not just existing i18n
+	// directories
+	rememberWatchedDirectory(directory)
+
 	// Read the directory
 	fileInfos, err := ioutil.ReadDir(directory)
 	if err != nil {
@@ -113,7 +149,7 @@ func searchDirectory(directory string, pwd string) {
 			}
 
 			// Look for more sub-directories
-			searchDirectory(fullPath, pwd)
+			searchDirectoryVisited(fullPath, pwd, visited)
 			continue
 		}
 	}
@@ -122,6 +158,9 @@ func searchDirectory(directory string, pwd string) {
 // loadTranslationFiles loads the found translation files into the i18n
 // messaging system for use by the application
 func loadTranslationFiles(directory string) {
+	// Remember this directory so Watch can pick up future changes to it
+	rememberWatchedDirectory(directory)
+
 	// Read the directory
 	fileInfos, err := ioutil.ReadDir(directory)
 	if err != nil {
@@ -0,0 +1,313 @@
+package localize
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/goinggo/tracelog"
+	"github.com/nicksnyder/go-i18n/i18n"
+)
+
+type (
+	// LocaleEvent reports that Watch loaded or reloaded a translation file,
+	// so applications can log the change or invalidate template caches
+	LocaleEvent struct {
+		Locale string
+		File   string
+		Op     string
+	}
+)
+
+const (
+	// EVENT_ADDED reports a translation file found in an i18n directory that
+	// Watch discovered after it started
+	EVENT_ADDED = "added"
+
+	// EVENT_UPDATED reports a translation file that changed on disk inside an
+	// i18n directory already being watched. A file newly created in such a
+	// directory is reported as updated too, not as added
+	EVENT_UPDATED = "updated"
+
+	// debounceWindow coalesces bursts of Write/Rename/Remove events for the
+	// same file into a single reload
+	debounceWindow = 200 * time.Millisecond
+)
+
+var (
+	tMu      sync.RWMutex
+	currentT i18n.TranslateFunc
+
+	localesMu          sync.Mutex
+	watchUserLocale    string
+	watchDefaultLocale string
+
+	watchedDirsMu sync.Mutex
+	watchedDirs   = map[string]bool{}
+
+	subscribersMu sync.Mutex
+	subscribers   []chan LocaleEvent
+)
+
+// CurrentT returns the translate function most recently installed by
+// LoadJSON, LoadFiles or a Watch-triggered reload, in a way that is safe to
+// call concurrently with those. T itself is just a thin wrapper around this
+func CurrentT() i18n.TranslateFunc {
+	tMu.RLock()
+	defer tMu.RUnlock()
+	return currentT
+}
+
+// setT atomically swaps the translator CurrentT (and so T) returns, so
+// concurrent request handlers never observe a torn translator
+func setT(t i18n.TranslateFunc) {
+	tMu.Lock()
+	currentT = t
+	tMu.Unlock()
+}
+
+// rememberLocales records the locale pair a Load* call used, so Watch can
+// recompute T with the same pair after a translation file reloads
+func rememberLocales(userLocale string, defaultLocale string) {
+	localesMu.Lock()
+	watchUserLocale = userLocale
+	watchDefaultLocale = defaultLocale
+	localesMu.Unlock()
+}
+
+// rememberWatchedDirectory records a directory LoadFiles walked, i18n
+// directories included, so Watch knows to put an fsnotify watch on it
+func rememberWatchedDirectory(directory string) {
+	watchedDirsMu.Lock()
+	watchedDirs[directory] = true
+	watchedDirsMu.Unlock()
+}
+
+// Subscribe returns a channel that receives a LocaleEvent whenever Watch
+// loads or reloads a translation file. The channel is buffered and never
+// closed; a slow subscriber misses events rather than blocking Watch
+func Subscribe() <-chan LocaleEvent {
+	ch := make(chan LocaleEvent, 16)
+
+	subscribersMu.Lock()
+	subscribers = append(subscribers, ch)
+	subscribersMu.Unlock()
+
+	return ch
+}
+
+// publish notifies subscribers of a locale load/reload, dropping the event
+// for any subscriber whose channel is currently full
+func publish(event LocaleEvent) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	for _, ch := range subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Watch registers an fsnotify watcher on every directory LoadFiles has walked
+// so far, i18n directories included, reloading translation files as they change on
+// disk until ctx is cancelled. New i18n directories created under a watched
+// directory are discovered and watched automatically
+func Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		tracelog.COMPLETED_ERROR(err, "localize", "Watch")
+		return err
+	}
+
+	watchedDirsMu.Lock()
+	for directory := range watchedDirs {
+		if err := watcher.Add(directory); err != nil {
+			tracelog.COMPLETED_ERROR(err, "localize", "Watch")
+		}
+	}
+	watchedDirsMu.Unlock()
+
+	go watchLoop(ctx, watcher)
+	return nil
+}
+
+// shouldReload reports whether an fsnotify event on a JSON translation file
+// should trigger a debounced reload. A deleted-then-recreated file (editors
+// and some filesystems emit Remove followed by Create) must still reload,
+// so Remove debounces just like Write/Create/Rename
+func shouldReload(op fsnotify.Op) bool {
+	return op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0
+}
+
+// watchLoop drains fsnotify events until ctx is cancelled, debouncing bursts
+// of events for the same file before reloading it
+func watchLoop(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+
+	var timersMu sync.Mutex
+	timers := map[string]*time.Timer{}
+
+	debounceReload := func(file string) {
+		timersMu.Lock()
+		defer timersMu.Unlock()
+
+		if timer, ok := timers[file]; ok {
+			timer.Reset(debounceWindow)
+			return
+		}
+
+		timers[file] = time.AfterFunc(debounceWindow, func() {
+			timersMu.Lock()
+			delete(timers, file)
+			timersMu.Unlock()
+
+			reloadFile(file)
+		})
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-watcher.Events:
+			if ok == false {
+				return
+			}
+
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				watchNewPath(watcher, event.Name)
+			}
+
+			if path.Ext(event.Name) != ".json" {
+				continue
+			}
+
+			if shouldReload(event.Op) {
+				debounceReload(event.Name)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if ok == false {
+				return
+			}
+
+			tracelog.COMPLETED_ERROR(err, "localize", "watchLoop")
+		}
+	}
+}
+
+// watchNewPath handles a Create event: if the new path is itself an i18n
+// directory, or a directory that contains one, it is loaded and watched; if
+// it is some other new directory, it is re-scanned recursively so nested
+// i18n directories created alongside it are still discovered
+func watchNewPath(watcher *fsnotify.Watcher, fullPath string) {
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() == false {
+		return
+	}
+
+	if filepath.Base(fullPath) == "i18n" {
+		watchI18nDirectory(watcher, fullPath)
+		return
+	}
+
+	searchDirectory(fullPath, fullPath)
+
+	watchedDirsMu.Lock()
+	var found []string
+	for directory := range watchedDirs {
+		if directory == fullPath || strings.HasPrefix(directory, fullPath+"/") {
+			found = append(found, directory)
+		}
+	}
+	watchedDirsMu.Unlock()
+
+	for _, directory := range found {
+		if filepath.Base(directory) == "i18n" {
+			watchI18nDirectory(watcher, directory)
+			continue
+		}
+
+		watcher.Add(directory)
+	}
+}
+
+// watchI18nDirectory loads a newly discovered i18n directory's translation
+// files, adds it to the watcher and notifies subscribers
+func watchI18nDirectory(watcher *fsnotify.Watcher, directory string) {
+	loadTranslationFiles(directory)
+
+	if err := watcher.Add(directory); err != nil {
+		tracelog.COMPLETED_ERROR(err, "localize", "watchI18nDirectory")
+	}
+
+	refreshT()
+
+	fileInfos, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return
+	}
+
+	for _, fileInfo := range fileInfos {
+		if path.Ext(fileInfo.Name()) != ".json" {
+			continue
+		}
+
+		file := fmt.Sprintf("%s/%s", directory, fileInfo.Name())
+		publish(LocaleEvent{Locale: localeFromFile(file), File: file, Op: EVENT_ADDED})
+	}
+}
+
+// reloadFile reloads a single translation file that changed on disk and
+// refreshes the package-level translate function to pick up the change
+func reloadFile(file string) {
+	if _, err := os.Stat(file); err != nil {
+		// The file was removed; go-i18n has no unload API, so there is
+		// nothing further to do beyond leaving the last-loaded strings in
+		// place
+		return
+	}
+
+	tracelog.INFO("localize", "reloadFile", "Reloading %s", file)
+	i18n.MustLoadTranslationFile(file)
+	refreshT()
+
+	publish(LocaleEvent{Locale: localeFromFile(file), File: file, Op: EVENT_UPDATED})
+}
+
+// refreshT recomputes T from the locale pair LoadFiles/LoadJSON was called
+// with, so a reloaded translation file is reflected immediately
+func refreshT() {
+	localesMu.Lock()
+	userLocale, defaultLocale := watchUserLocale, watchDefaultLocale
+	localesMu.Unlock()
+
+	if userLocale == "" {
+		return
+	}
+
+	t, err := i18n.Tfunc(userLocale, defaultLocale)
+	if err != nil {
+		tracelog.COMPLETED_ERROR(err, "localize", "refreshT")
+		return
+	}
+
+	setT(t)
+}
+
+// localeFromFile guesses the locale a translation file covers from its
+// name, e.g. "en-us.json" -> "en-us"
+func localeFromFile(file string) string {
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
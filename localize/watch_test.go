@@ -0,0 +1,173 @@
+package localize
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestShouldReload(t *testing.T) {
+	cases := []struct {
+		name string
+		op   fsnotify.Op
+		want bool
+	}{
+		{name: "write", op: fsnotify.Write, want: true},
+		{name: "create", op: fsnotify.Create, want: true},
+		{name: "rename", op: fsnotify.Rename, want: true},
+		{name: "remove", op: fsnotify.Remove, want: true},
+		{name: "chmod only does not reload", op: fsnotify.Chmod, want: false},
+		{name: "remove then create still reloads", op: fsnotify.Remove | fsnotify.Create, want: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := shouldReload(c.op); got != c.want {
+				t.Errorf("shouldReload(%v) = %v, want %v", c.op, got, c.want)
+			}
+		})
+	}
+}
+
+// TestWatchReloadsChangedTranslationFile exercises the real Watch/watchLoop/
+// reloadFile path end to end: LoadFiles discovers an i18n directory on disk,
+// Watch puts an fsnotify watcher on it, and editing the translation file
+// should publish a LocaleEvent once reloadFile picks up the change.
+func TestWatchReloadsChangedTranslationFile(t *testing.T) {
+	dir := t.TempDir()
+	i18nDir := filepath.Join(dir, "i18n")
+	if err := os.Mkdir(i18nDir, 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	file := filepath.Join(i18nDir, "en-us.json")
+	writeTranslation(t, file, "Hello")
+
+	restoreWd := chdir(t, dir)
+	defer restoreWd()
+
+	restoreGopath := unsetGopath(t)
+	defer restoreGopath()
+
+	if err := LoadFiles("en-us", "en-us"); err != nil {
+		t.Fatalf("LoadFiles: %v", err)
+	}
+
+	events := Subscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := Watch(ctx); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	writeTranslation(t, file, "Hello there")
+
+	select {
+	case event := <-events:
+		if event.Op != EVENT_UPDATED {
+			t.Errorf("Op = %q, want %q", event.Op, EVENT_UPDATED)
+		}
+
+		if event.File != file {
+			t.Errorf("File = %q, want %q", event.File, file)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a reload event")
+	}
+}
+
+// TestWatchDiscoversNewI18nDirectory exercises the gap a new i18n directory
+// created under an already-watched (but not itself i18n) directory must be
+// picked up: the parent has to already carry an fsnotify watch for the
+// Create event to arrive at all.
+func TestWatchDiscoversNewI18nDirectory(t *testing.T) {
+	dir := t.TempDir()
+	plugins := filepath.Join(dir, "plugins")
+	if err := os.Mkdir(plugins, 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	restoreWd := chdir(t, dir)
+	defer restoreWd()
+
+	restoreGopath := unsetGopath(t)
+	defer restoreGopath()
+
+	if err := LoadFiles("en-us", "en-us"); err != nil {
+		t.Fatalf("LoadFiles: %v", err)
+	}
+
+	events := Subscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := Watch(ctx); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	newI18nDir := filepath.Join(plugins, "i18n")
+	if err := os.Mkdir(newI18nDir, 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	writeTranslation(t, filepath.Join(newI18nDir, "en-us.json"), "Plugin hello")
+
+	select {
+	case event := <-events:
+		if event.Op != EVENT_ADDED {
+			t.Errorf("Op = %q, want %q", event.Op, EVENT_ADDED)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the new i18n directory to be discovered")
+	}
+}
+
+func writeTranslation(t *testing.T, file string, hello string) {
+	t.Helper()
+
+	doc := fmt.Sprintf(`[{"id": "Hello", "translation": %q}]`, hello)
+	if err := os.WriteFile(file, []byte(doc), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func chdir(t *testing.T, dir string) func() {
+	t.Helper()
+
+	oldwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+
+	return func() {
+		if err := os.Chdir(oldwd); err != nil {
+			t.Fatalf("Chdir back to %s: %v", oldwd, err)
+		}
+	}
+}
+
+func unsetGopath(t *testing.T) func() {
+	t.Helper()
+
+	old, had := os.LookupEnv("GOPATH")
+	if err := os.Unsetenv("GOPATH"); err != nil {
+		t.Fatalf("Unsetenv: %v", err)
+	}
+
+	return func() {
+		if had {
+			os.Setenv("GOPATH", old)
+		}
+	}
+}
@@ -4,12 +4,12 @@ package web
 import (
 	"fmt"
 	"reflect"
+	"runtime"
 
 	aErrors "github.com/ArdanStudios/go-common/errors"
-	"github.com/ArdanStudios/go-common/helper"
 	"github.com/astaxie/beego"
 	"github.com/astaxie/beego/validation"
-	"github.com/goinggo/tracelog"
+	"github.com/nicksnyder/go-i18n/i18n"
 )
 
 type (
@@ -29,6 +29,45 @@ const (
 	CACHE_CONTROL_HEADER = "Cache-control"
 )
 
+// Prepare runs before every action and stashes a translate function for the
+// request's locale on the controller so it, and the Serve* helpers below,
+// can resolve message IDs through T. Embedding controllers that define
+// their own Prepare are not required to call this one: T resolves the
+// translator itself on first use if it isn't already stashed
+func (this *BaseController) Prepare() {
+	this.Data["T"] = Locale(&this.Controller)
+}
+
+// T translates the message identified by id for the current request's
+// locale, substituting any template args. Controllers embedding
+// BaseController should call this instead of hard-coding message strings.
+// The translator is resolved from the request on first use, so T works
+// correctly even if an embedding controller overrides Prepare without
+// calling this.BaseController.Prepare() first
+func (this *BaseController) T(id string, args ...interface{}) string {
+	t, ok := this.Data["T"].(i18n.TranslateFunc)
+	if !ok {
+		t = Locale(&this.Controller)
+		this.Data["T"] = t
+	}
+
+	return t(id, args...)
+}
+
+// Log returns the structured Logger for the current request, installed by
+// LoggingMiddleware. Controllers not served behind that middleware get a
+// Logger with no request ID rather than a nil one
+func (this *BaseController) Log() Logger {
+	return loggerFromContext(this.Ctx.Request.Context())
+}
+
+// RequestID returns the current request's ID: the X-Request-Id header it
+// arrived with, or the ID LoggingMiddleware generated when that header was
+// absent. Empty when the controller is not served behind LoggingMiddleware
+func (this *BaseController) RequestID() string {
+	return requestIDFromContext(this.Ctx.Request.Context())
+}
+
 // CacheOutput outputs the cache control headrer for seconds passed in
 func (this *BaseController) CacheOutput(seconds int64) {
 	this.Ctx.Output.Header(CACHE_CONTROL_HEADER, fmt.Sprintf("private, must-revalidate, max-age=%d", seconds))
@@ -63,53 +102,121 @@ func (this *BaseController) ServeJsonWithCache(obj interface{}, secondsToCache i
 
 // ServeUnAuthorized returns an Unauthorized error
 func (this *BaseController) ServeUnAuthorized() {
-	tracelog.INFO("BaseController", "ServeUnAuthorized", "UnAuthorized, Exiting")
+	this.Log().Info("BaseController", "ServeUnAuthorized", "UnAuthorized, Exiting")
 
-	this.ServeMessageWithStatus(aErrors.UNAUTHORIZED_ERROR_CODE, aErrors.UNAUTHORIZED_ERROR_MSG)
+	this.ServeMessageWithStatus(aErrors.UNAUTHORIZED_ERROR_CODE, this.T("UnAuthorized"))
 	return
 }
 
 // ServeValidationError returns a Validation Error's list of messages with a validation err code.
 func (this *BaseController) ServeValidationError() {
-	this.Ctx.Output.SetStatus(aErrors.VALIDATION_ERROR_CODE)
+	if this.problemDetailsEnabled() {
+		this.ServeProblem(&ProblemDetails{
+			Type:   this.problemType(aErrors.VALIDATION_ERROR_CODE),
+			Title:  this.T("ValidationError"),
+			Status: aErrors.VALIDATION_ERROR_CODE,
+			Code:   aErrors.VALIDATION_ERROR_CODE,
+		})
+		return
+	}
 
-	msgs := MessageResponse{}
-	msgs.Messages = []string{aErrors.VALIDATION_ERROR_MSG}
-	this.Data["json"] = &msgs
-	this.ServeJson()
+	msgs := MessageResponse{Messages: []string{this.T("ValidationError")}}
+	this.writeNegotiated(aErrors.VALIDATION_ERROR_CODE, &msgs)
+}
+
+// fieldOverride is a per-field message preference read from a params
+// struct's "error_id"/"error" tags by ParseAndValidate, honored ahead of
+// ServeValidationErrors' generic "ValidationField" translation
+type fieldOverride struct {
+	messageID string
+	message   string
 }
 
 // ServeValidationErrors returns a Validation Error's list of messages with a validation err code.
-func (this *BaseController) ServeValidationErrors(validationErrors []*validation.ValidationError) {
+func (this *BaseController) ServeValidationErrors(validationErrors []*validation.ValidationError, overrides ...map[string]fieldOverride) {
+	var override map[string]fieldOverride
+	if len(overrides) > 0 {
+		override = overrides[0]
+	}
+
+	if this.problemDetailsEnabled() {
+		fieldErrors := make([]FieldError, len(validationErrors))
+		for index, validationError := range validationErrors {
+			fieldErrors[index] = FieldError{
+				Field:   validationError.Field,
+				Code:    validationError.Key,
+				Message: this.validationFieldMessage(validationError, override),
+			}
+		}
+
+		this.ServeProblem(&ProblemDetails{
+			Type:   this.problemType(aErrors.VALIDATION_ERROR_CODE),
+			Title:  this.T("ValidationError"),
+			Status: aErrors.VALIDATION_ERROR_CODE,
+			Code:   aErrors.VALIDATION_ERROR_CODE,
+			Errors: fieldErrors,
+		})
+		return
+	}
+
 	this.Ctx.Output.SetStatus(aErrors.VALIDATION_ERROR_CODE)
 
 	response := make([]string, len(validationErrors))
 	for index, validationError := range validationErrors {
-		response[index] = fmt.Sprintf("%s: %s", validationError.Field, validationError.String())
+		response[index] = this.validationFieldMessage(validationError, override)
 	}
 
-	msgs := MessageResponse{}
-	msgs.Messages = response
+	msgs := MessageResponse{Messages: response}
 	this.Data["json"] = &msgs
 	this.ServeJson()
 }
 
+// validationFieldMessage resolves the text to serve for a single field
+// validation failure: override's translated error_id or static error tag
+// when the field has one, the generic "ValidationField" translation
+// otherwise
+func (this *BaseController) validationFieldMessage(validationError *validation.ValidationError, override map[string]fieldOverride) string {
+	if fo, ok := override[validationError.Field]; ok {
+		if fo.messageID != "" {
+			return this.T(fo.messageID)
+		}
+
+		if fo.message != "" {
+			return fo.message
+		}
+	}
+
+	return this.T("ValidationField", map[string]interface{}{
+		"Field":  validationError.Field,
+		"Reason": validationError.String(),
+	})
+}
+
 // ServeError serves a error interface object.
 func (this *BaseController) ServeError(err error) {
-	tracelog.INFO("BaseController", "ServeError", "Application Error, Exiting")
+	this.Log().Info("BaseController", "ServeError", "Application Error, Exiting")
 
 	switch e := err.(type) {
 	case *aErrors.AppError:
-
+		code := aErrors.APP_ERROR_CODE
 		if e.ErrorCode() != 0 {
-			this.ServeMessageWithStatus(e.ErrorCode(), e.Error())
+			code = e.ErrorCode()
+		}
 
-		} else {
-			this.ServeMessageWithStatus(aErrors.APP_ERROR_CODE, e.Error())
+		if this.problemDetailsEnabled() {
+			this.ServeProblem(this.appErrorProblem(code, e.Error()))
+			return
 		}
 
+		this.ServeMessageWithStatus(code, e.Error())
+
 	default:
-		this.ServeMessageWithStatus(aErrors.APP_ERROR_CODE, aErrors.APP_ERROR_MSG)
+		if this.problemDetailsEnabled() {
+			this.ServeProblem(this.appErrorProblem(aErrors.APP_ERROR_CODE, this.T("AppError")))
+			return
+		}
+
+		this.ServeMessageWithStatus(aErrors.APP_ERROR_CODE, this.T("AppError"))
 	}
 
 	return
@@ -117,19 +224,29 @@ func (this *BaseController) ServeError(err error) {
 
 // ServeErrorResponse serves an error interface object
 func (this *BaseController) ServeErrorResponse(err error) {
-	tracelog.INFO("BaseController", "ServeErrorResponse", "Application Error, Exiting : %s", err)
+	this.Log().Info("BaseController", "ServeErrorResponse", "Application Error, Exiting : %s", err)
 
 	switch e := err.(type) {
 	case *aErrors.AppError:
+		code := aErrors.APP_ERROR_CODE
 		if e.ErrorCode() != 0 {
-			this.ServeMessageWithStatus(e.ErrorCode(), e.Error())
+			code = e.ErrorCode()
+		}
+
+		if this.problemDetailsEnabled() {
+			this.ServeProblem(this.appErrorProblem(code, e.Error()))
 			return
 		}
 
-		this.ServeMessageWithStatus(aErrors.APP_ERROR_CODE, e.Error())
+		this.ServeMessageWithStatus(code, e.Error())
 		return
 
 	default:
+		if this.problemDetailsEnabled() {
+			this.ServeProblem(this.appErrorProblem(aErrors.APP_ERROR_CODE, err.Error()))
+			return
+		}
+
 		this.ServeMessageWithStatus(aErrors.APP_ERROR_CODE, err.Error())
 		return
 	}
@@ -137,9 +254,9 @@ func (this *BaseController) ServeErrorResponse(err error) {
 
 // ServeAppError serves a generic application error
 func (this *BaseController) ServeAppError() {
-	tracelog.INFO("BaseController", "ServeAppError", "Application Error, Exiting")
+	this.Log().Info("BaseController", "ServeAppError", "Application Error, Exiting")
 
-	this.ServeMessageWithStatus(aErrors.APP_ERROR_CODE, aErrors.APP_ERROR_MSG)
+	this.ServeMessageWithStatus(aErrors.APP_ERROR_CODE, this.T("AppError"))
 	return
 }
 
@@ -150,59 +267,71 @@ func (this *BaseController) ServeMessageWithStatus(status int, msg string) {
 
 // ServeMessageWithStatus serves a HTTP status and messages
 func (this *BaseController) ServeMessagesWithStatus(status int, msgs []string) {
-	this.Ctx.Output.SetStatus(status)
 	response := MessageResponse{Messages: msgs}
-	this.Data["json"] = &response
-	this.ServeJson()
+	this.writeNegotiated(status, &response)
 }
 
 // ParseAndValidate is used to parse any form and query parameters from the request and validate the values
 func (this *BaseController) ParseAndValidate(params interface{}) bool {
 	err := this.ParseForm(params)
 	if err != nil {
-		this.ServeMessageWithStatus(aErrors.VALIDATION_ERROR_CODE, aErrors.VALIDATION_ERROR_MSG)
+		this.ServeMessageWithStatus(aErrors.VALIDATION_ERROR_CODE, this.T("ValidationError"))
 		return false
 	}
 
 	valid := validation.Validation{}
 	ok, err := valid.Valid(params)
 	if err != nil {
-		this.ServeMessageWithStatus(aErrors.VALIDATION_ERROR_CODE, aErrors.VALIDATION_ERROR_MSG)
+		this.ServeMessageWithStatus(aErrors.VALIDATION_ERROR_CODE, this.T("ValidationError"))
 		return false
 	}
 
 	if ok == false {
-		// Build a map of the error messages
-		messages2 := map[string]string{}
+		// Build a map of the per-field overrides read from the "error" and
+		// "error_id" struct tags, and delegate to ServeValidationErrors so
+		// UseProblemDetails/RFC7807 is honored the same as any other
+		// validation failure
+		overrides := map[string]fieldOverride{}
 		val := reflect.ValueOf(params).Elem()
 		for i := 0; i < val.NumField(); i++ {
 			typeField := val.Type().Field(i)
 			tag := typeField.Tag
-			tagValue := tag.Get("error")
-			messages2[typeField.Name] = tagValue
-		}
-
-		// Build the error response
-		errors := []string{}
-		for _, err := range valid.Errors {
-			message, ok := messages2[err.Field]
-			if ok == true {
-				errors = append(errors, message)
-			} else {
-				errors = append(errors, err.Message)
+			overrides[typeField.Name] = fieldOverride{
+				messageID: tag.Get("error_id"),
+				message:   tag.Get("error"),
 			}
 		}
 
-		this.ServeMessagesWithStatus(aErrors.VALIDATION_ERROR_CODE, errors)
+		this.ServeValidationErrors(valid.Errors, overrides)
 		return false
 	}
 
 	return true
 }
 
-// CatchPanic is used to stop and process panics before they reach the Go runtime
+// CatchPanic is used to stop and process panics before they reach the Go
+// runtime. Call it deferred from the top of an action:
+// defer this.CatchPanic(&err, "UUID", "Controller.Action"). Panics are
+// logged through Log(), enriched with the goroutine's stack, rather than
+// the tracelog singleton
 func (this *BaseController) CatchPanic(err *error, UUID string, functionName string) {
-	if helper.CatchPanic(err, UUID, functionName) {
-		this.ServeAppError()
+	recovered := recover()
+	if recovered == nil {
+		return
 	}
+
+	stack := make([]byte, 4096)
+	n := runtime.Stack(stack, false)
+
+	this.Log().Error(
+		fmt.Errorf("%v", recovered),
+		"BaseController",
+		fmt.Sprintf("%s : PANIC Defered [%s] : goroutine[%s] stack:\n%s", functionName, UUID, goroutineID(stack[:n]), stack[:n]),
+	)
+
+	if err != nil {
+		*err = fmt.Errorf("%v", recovered)
+	}
+
+	this.ServeAppError()
 }
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"github.com/astaxie/beego"
+	"github.com/nicksnyder/go-i18n/i18n"
+)
+
+const (
+	// DEFAULT_LOCALE_CONFIG_KEY is the beego app.conf key used to look up the
+	// locale to fall back to when a request has no Accept-Language header or
+	// asks for a locale that has no translations loaded
+	DEFAULT_LOCALE_CONFIG_KEY = "app.default_locale"
+
+	// DEFAULT_LOCALE is used when app.default_locale is not set in app.conf
+	DEFAULT_LOCALE = "en-US"
+)
+
+// Locale inspects the Accept-Language header on the controller's request
+// and returns a translate function for the best matching locale, falling
+// back to the locale configured via app.default_locale (or DEFAULT_LOCALE)
+// when no Accept-Language header is present or no translations have been
+// loaded for the requested locale
+func Locale(ctrl *beego.Controller) i18n.TranslateFunc {
+	defaultLocale, err := beego.AppConfig.String(DEFAULT_LOCALE_CONFIG_KEY)
+	if err != nil || defaultLocale == "" {
+		defaultLocale = DEFAULT_LOCALE
+	}
+
+	userLocale := defaultLocale
+	if accept := ctrl.Ctx.Input.Header("Accept-Language"); accept != "" {
+		userLocale = preferredLocale(accept)
+	}
+
+	tFunc, err := i18n.Tfunc(userLocale, defaultLocale)
+	if err != nil {
+		tFunc, _ = i18n.Tfunc(defaultLocale)
+	}
+
+	return tFunc
+}
+
+// preferredLocale returns the highest priority locale tag found in an
+// Accept-Language header value, e.g. "fr-FR,fr;q=0.9,en;q=0.8" -> "fr-FR"
+func preferredLocale(header string) string {
+	tags := parseAccept(header)
+	if len(tags) == 0 {
+		return ""
+	}
+
+	return tags[0].mediaType
+}
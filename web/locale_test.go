@@ -0,0 +1,61 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/astaxie/beego"
+	"github.com/astaxie/beego/context"
+)
+
+func newTestController(t *testing.T, acceptLanguage string) *beego.Controller {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptLanguage != "" {
+		r.Header.Set("Accept-Language", acceptLanguage)
+	}
+
+	ctx := context.NewContext()
+	ctx.Reset(httptest.NewRecorder(), r)
+
+	return &beego.Controller{Ctx: ctx}
+}
+
+func TestPreferredLocale(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "single locale", header: "fr-FR", want: "fr-FR"},
+		{name: "locale with q value", header: "fr-FR;q=0.8", want: "fr-FR"},
+		{name: "first of several", header: "fr-FR,fr;q=0.9,en;q=0.8", want: "fr-FR"},
+		{name: "whitespace trimmed", header: " en-US ", want: "en-US"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := preferredLocale(c.header); got != c.want {
+				t.Errorf("preferredLocale(%q) = %q, want %q", c.header, got, c.want)
+			}
+		})
+	}
+}
+
+func TestLocaleReturnsTranslatorWithoutAcceptLanguage(t *testing.T) {
+	controller := newTestController(t, "")
+
+	if tFunc := Locale(controller); tFunc == nil {
+		t.Fatal("Locale returned a nil translate function")
+	}
+}
+
+func TestLocaleReturnsTranslatorWithAcceptLanguage(t *testing.T) {
+	controller := newTestController(t, "fr-FR,en;q=0.8")
+
+	if tFunc := Locale(controller); tFunc == nil {
+		t.Fatal("Locale returned a nil translate function")
+	}
+}
@@ -0,0 +1,163 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/goinggo/tracelog"
+)
+
+type (
+	// Logger is the structured, per-request logging interface BaseController
+	// and its Serve* helpers log through
+	Logger interface {
+		Info(context string, function string, format string, args ...interface{})
+		Error(err error, context string, function string)
+	}
+
+	// RequestLogger is the Logger LoggingMiddleware installs on the request
+	// context, tagging every record it writes with the request's ID
+	RequestLogger struct {
+		RequestID string
+	}
+
+	contextKey int
+)
+
+const (
+	// REQUEST_ID_HEADER is read on inbound requests and echoed back on the
+	// response so a request can be correlated across services
+	REQUEST_ID_HEADER = "X-Request-Id"
+
+	loggerContextKey contextKey = iota
+	requestIDContextKey
+)
+
+// Info logs a structured INFO record tagged with the logger's request ID
+func (this *RequestLogger) Info(ctx string, function string, format string, args ...interface{}) {
+	tracelog.INFO(ctx, function, "[%s] %s", this.RequestID, fmt.Sprintf(format, args...))
+}
+
+// Error logs a structured ERROR record tagged with the logger's request ID
+func (this *RequestLogger) Error(err error, ctx string, function string) {
+	tracelog.COMPLETED_ERROR(err, ctx, fmt.Sprintf("[%s] %s", this.RequestID, function))
+}
+
+// LoggingMiddleware wraps next to produce one structured log record per
+// request (method, path, remote addr, status, bytes written, duration and
+// request ID), and installs a Logger and request ID on the request context
+// for BaseController.Log/RequestID to pick up. The request ID is read from
+// X-Request-Id if present, generated otherwise, and always echoed back on
+// the response so callers can correlate it across services
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(REQUEST_ID_HEADER)
+		if requestID == "" {
+			requestID = generateRequestID()
+		}
+		w.Header().Set(REQUEST_ID_HEADER, requestID)
+
+		logger := &RequestLogger{RequestID: requestID}
+		ctx := context.WithValue(r.Context(), loggerContextKey, logger)
+		ctx = context.WithValue(ctx, requestIDContextKey, requestID)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		start := time.Now()
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+		duration := time.Since(start)
+
+		logger.Info("web", "LoggingMiddleware", "method=%s path=%s remote=%s status=%d bytes=%d duration=%s",
+			r.Method, r.URL.Path, r.RemoteAddr, recorder.status, recorder.bytes, duration)
+	})
+}
+
+// RecoveryMiddleware recovers any panic that escapes next, logging an
+// enriched record (goroutine ID, stack, request ID) through the Logger
+// LoggingMiddleware installed, then responds with 500. Install it inside
+// LoggingMiddleware so the panic record still carries a request ID
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				stack := make([]byte, 4096)
+				n := runtime.Stack(stack, false)
+
+				loggerFromContext(r.Context()).Error(
+					fmt.Errorf("%v", recovered),
+					"web",
+					fmt.Sprintf("RecoveryMiddleware : PANIC : goroutine[%s] stack:\n%s", goroutineID(stack[:n]), stack[:n]),
+				)
+
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder wraps http.ResponseWriter to capture the status code and
+// byte count LoggingMiddleware reports once the handler returns
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (this *statusRecorder) WriteHeader(status int) {
+	this.status = status
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusRecorder) Write(b []byte) (int, error) {
+	n, err := this.ResponseWriter.Write(b)
+	this.bytes += n
+	return n, err
+}
+
+// loggerFromContext returns the Logger LoggingMiddleware installed, or a
+// Logger with no request ID when the context was never wrapped by it
+func loggerFromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerContextKey).(*RequestLogger); ok {
+		return logger
+	}
+
+	return &RequestLogger{}
+}
+
+// requestIDFromContext returns the request ID LoggingMiddleware installed,
+// or "" when the context was never wrapped by it
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// generateRequestID creates a request ID for requests that arrive without
+// an X-Request-Id header
+func generateRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+// goroutineID extracts the numeric goroutine ID from the leading line of a
+// runtime.Stack dump, e.g. "goroutine 123 [running]:" -> "123"
+func goroutineID(stack []byte) string {
+	line := bytes.SplitN(stack, []byte("\n"), 2)[0]
+	fields := bytes.Fields(line)
+	if len(fields) < 2 {
+		return ""
+	}
+
+	return string(fields[1])
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoggingMiddlewareGeneratesAndEchoesRequestID(t *testing.T) {
+	var gotID string
+	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = requestIDFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if gotID == "" {
+		t.Fatal("expected a generated request ID in the handler's context")
+	}
+
+	if got := rec.Header().Get(REQUEST_ID_HEADER); got != gotID {
+		t.Errorf("response header %s = %q, want %q", REQUEST_ID_HEADER, got, gotID)
+	}
+
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTeapot)
+	}
+}
+
+func TestLoggingMiddlewarePreservesIncomingRequestID(t *testing.T) {
+	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(REQUEST_ID_HEADER, "caller-supplied-id")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(REQUEST_ID_HEADER); got != "caller-supplied-id" {
+		t.Errorf("response header %s = %q, want %q", REQUEST_ID_HEADER, got, "caller-supplied-id")
+	}
+}
+
+func TestRecoveryMiddlewareRecoversPanic(t *testing.T) {
+	handler := LoggingMiddleware(RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	cases := []struct {
+		name  string
+		stack []byte
+		want  string
+	}{
+		{
+			name:  "typical stack header",
+			stack: []byte("goroutine 42 [running]:\nmain.main()\n\t/app/main.go:10\n"),
+			want:  "42",
+		},
+		{
+			name:  "missing fields",
+			stack: []byte(""),
+			want:  "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := goroutineID(c.stack); got != c.want {
+				t.Errorf("goroutineID(%q) = %q, want %q", c.stack, got, c.want)
+			}
+		})
+	}
+}
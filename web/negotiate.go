@@ -0,0 +1,198 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// Encoder encodes a response body for a negotiated media type
+	Encoder interface {
+		ContentType() string
+		Encode(obj interface{}) ([]byte, error)
+	}
+
+	// acceptEntry is a single, parsed media-range from an Accept header
+	acceptEntry struct {
+		mediaType string
+		q         float64
+	}
+
+	jsonEncoder struct{}
+)
+
+const (
+	// JSON_CONTENT_TYPE is the media type registered by default and used as
+	// the fallback when a request's Accept header can't be satisfied but
+	// includes a wildcard
+	JSON_CONTENT_TYPE = "application/json"
+)
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]Encoder{
+		JSON_CONTENT_TYPE: jsonEncoder{},
+	}
+)
+
+// RegisterEncoder adds, or replaces, the Encoder used to satisfy mediaType
+// so downstream applications can add codecs (XML, MessagePack, protobuf,
+// ...) to ServeModel/ServeModelWithCache without forking this package
+func RegisterEncoder(mediaType string, enc Encoder) {
+	encodersMu.Lock()
+	encoders[mediaType] = enc
+	encodersMu.Unlock()
+}
+
+func (jsonEncoder) ContentType() string {
+	return JSON_CONTENT_TYPE
+}
+
+func (jsonEncoder) Encode(obj interface{}) ([]byte, error) {
+	return json.Marshal(obj)
+}
+
+// ServeModel negotiates the response encoding from the request's Accept
+// header against the registered encoders and writes obj with the best
+// match, defaulting to JSON when the client sends no preference
+func (this *BaseController) ServeModel(obj interface{}) {
+	this.ServeModelWithCache(obj, 0)
+}
+
+// ServeModelWithCache is ServeModel, additionally setting a Cache-control
+// header for secondsToCache seconds
+func (this *BaseController) ServeModelWithCache(obj interface{}, secondsToCache int64) {
+	if secondsToCache > 0 {
+		this.CacheOutput(secondsToCache)
+	}
+
+	this.writeNegotiated(http.StatusOK, obj)
+}
+
+// writeNegotiated encodes obj with the Encoder matching the request's
+// Accept header and writes it with status, serving 406 Not Acceptable when
+// no registered encoder satisfies the header
+func (this *BaseController) writeNegotiated(status int, obj interface{}) {
+	this.writeNegotiatedWithContentType(status, obj, "")
+}
+
+// writeNegotiatedWithContentType is writeNegotiated, except that when
+// negotiation resolves to the JSON encoder the response Content-Type is
+// overridden with contentTypeOverride (if non-empty). This lets callers
+// like ServeProblem keep their more specific media type (e.g.
+// application/problem+json) while still honoring non-JSON Accept headers
+func (this *BaseController) writeNegotiatedWithContentType(status int, obj interface{}, contentTypeOverride string) {
+	enc, ok := negotiateEncoder(this.Ctx.Input.Header("Accept"))
+	if ok == false {
+		this.Ctx.Output.SetStatus(http.StatusNotAcceptable)
+		return
+	}
+
+	body, err := enc.Encode(obj)
+	if err != nil {
+		this.ServeAppError()
+		return
+	}
+
+	contentType := enc.ContentType()
+	if contentTypeOverride != "" && contentType == JSON_CONTENT_TYPE {
+		contentType = contentTypeOverride
+	}
+
+	this.Ctx.Output.SetStatus(status)
+	this.Ctx.Output.Header("Content-Type", contentType)
+	this.Ctx.Output.Body(body)
+}
+
+// negotiateEncoder picks the registered Encoder for the highest priority
+// media-range in accept that has one registered. It falls back to the JSON
+// encoder when accept is empty, unparsable, or only matches via a wildcard,
+// and reports false when accept names only media types with no encoder and
+// no wildcard to fall back on
+func negotiateEncoder(accept string) (Encoder, bool) {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	if accept == "" {
+		return encoders[JSON_CONTENT_TYPE], true
+	}
+
+	entries := parseAccept(accept)
+	if len(entries) == 0 {
+		return encoders[JSON_CONTENT_TYPE], true
+	}
+
+	sawWildcard := false
+	for _, entry := range entries {
+		// q=0 means "not acceptable" (RFC 7231 5.3.1): it must not match,
+		// and must not count as a wildcard fallback either
+		if entry.q <= 0 {
+			continue
+		}
+
+		if entry.mediaType == "*/*" {
+			sawWildcard = true
+			continue
+		}
+
+		if enc, ok := encoders[entry.mediaType]; ok {
+			return enc, true
+		}
+
+		if strings.HasSuffix(entry.mediaType, "/*") {
+			sawWildcard = true
+		}
+	}
+
+	if sawWildcard {
+		if enc, ok := encoders[JSON_CONTENT_TYPE]; ok {
+			return enc, true
+		}
+	}
+
+	return nil, false
+}
+
+// parseAccept splits an Accept header into its media-ranges, sorted from
+// highest to lowest q value (RFC 7231 5.3.2)
+func parseAccept(header string) []acceptEntry {
+	parts := strings.Split(header, ",")
+	entries := make([]acceptEntry, 0, len(parts))
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mediaType := part
+		q := 1.0
+
+		if idx := strings.Index(part, ";"); idx != -1 {
+			mediaType = strings.TrimSpace(part[:idx])
+
+			for _, param := range strings.Split(part[idx+1:], ";") {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") == false {
+					continue
+				}
+
+				if parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		entries = append(entries, acceptEntry{mediaType: mediaType, q: q})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].q > entries[j].q
+	})
+
+	return entries
+}
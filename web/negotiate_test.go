@@ -0,0 +1,83 @@
+package web
+
+import "testing"
+
+func TestParseAccept(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		want   []acceptEntry
+	}{
+		{
+			name:   "simple",
+			header: "application/json",
+			want:   []acceptEntry{{mediaType: "application/json", q: 1}},
+		},
+		{
+			name:   "sorted by q descending",
+			header: "text/html;q=0.5, application/json;q=0.9, */*;q=0.1",
+			want: []acceptEntry{
+				{mediaType: "application/json", q: 0.9},
+				{mediaType: "text/html", q: 0.5},
+				{mediaType: "*/*", q: 0.1},
+			},
+		},
+		{
+			name:   "blank entries ignored",
+			header: "application/json, , text/xml",
+			want: []acceptEntry{
+				{mediaType: "application/json", q: 1},
+				{mediaType: "text/xml", q: 1},
+			},
+		},
+		{
+			name:   "q=0 is preserved, not dropped",
+			header: "application/json;q=0",
+			want:   []acceptEntry{{mediaType: "application/json", q: 0}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := parseAccept(c.header)
+			if len(got) != len(c.want) {
+				t.Fatalf("got %d entries %+v, want %d entries %+v", len(got), got, len(c.want), c.want)
+			}
+
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("entry %d = %+v, want %+v", i, got[i], c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoder(t *testing.T) {
+	cases := []struct {
+		name      string
+		accept    string
+		wantMatch bool
+		wantType  string
+	}{
+		{name: "empty accept defaults to json", accept: "", wantMatch: true, wantType: JSON_CONTENT_TYPE},
+		{name: "exact json match", accept: "application/json", wantMatch: true, wantType: JSON_CONTENT_TYPE},
+		{name: "wildcard falls back to json", accept: "text/plain;q=0.1, */*;q=0.5", wantMatch: true, wantType: JSON_CONTENT_TYPE},
+		{name: "unregistered type with no wildcard is not acceptable", accept: "application/xml", wantMatch: false},
+		{name: "q=0 excludes a type even if it is the only one registered", accept: "application/json;q=0", wantMatch: false},
+		{name: "q=0 wildcard does not enable fallback", accept: "application/xml, */*;q=0", wantMatch: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			enc, ok := negotiateEncoder(c.accept)
+			if ok != c.wantMatch {
+				t.Fatalf("ok = %v, want %v", ok, c.wantMatch)
+			}
+
+			if ok && enc.ContentType() != c.wantType {
+				t.Errorf("content type = %s, want %s", enc.ContentType(), c.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/astaxie/beego"
+)
+
+func TestProblemType(t *testing.T) {
+	cases := []struct {
+		name string
+		base string
+		code int
+		want string
+	}{
+		{name: "unset base falls back to about:blank", base: "", code: 404, want: DEFAULT_PROBLEM_BASE_URL},
+		{name: "configured base appends the code", base: "https://api.example.com/problems", code: 404, want: "https://api.example.com/problems/404"},
+		{name: "trailing slash is trimmed", base: "https://api.example.com/problems/", code: 500, want: "https://api.example.com/problems/500"},
+	}
+
+	this := &BaseController{}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := beego.AppConfig.Set(PROBLEM_BASE_URL_CONFIG_KEY, c.base); err != nil {
+				t.Fatalf("AppConfig.Set: %v", err)
+			}
+
+			if got := this.problemType(c.code); got != c.want {
+				t.Errorf("problemType(%d) = %q, want %q", c.code, got, c.want)
+			}
+		})
+	}
+}
+
+func TestAppErrorProblem(t *testing.T) {
+	if err := beego.AppConfig.Set(PROBLEM_BASE_URL_CONFIG_KEY, ""); err != nil {
+		t.Fatalf("AppConfig.Set: %v", err)
+	}
+
+	this := &BaseController{Controller: *newTestController(t, "")}
+	this.Data = map[interface{}]interface{}{}
+
+	problem := this.appErrorProblem(512, "something went wrong")
+	if problem.Status != 512 {
+		t.Errorf("Status = %d, want 512", problem.Status)
+	}
+
+	if problem.Code != 512 {
+		t.Errorf("Code = %d, want 512", problem.Code)
+	}
+
+	if problem.Detail != "something went wrong" {
+		t.Errorf("Detail = %q, want %q", problem.Detail, "something went wrong")
+	}
+
+	if problem.Type != DEFAULT_PROBLEM_BASE_URL {
+		t.Errorf("Type = %q, want %q", problem.Type, DEFAULT_PROBLEM_BASE_URL)
+	}
+}